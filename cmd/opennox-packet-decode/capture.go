@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+var (
+	fIface  = flag.String("iface", "", "capture live traffic from this network interface instead of reading a file from -i")
+	fBPF    = flag.String("bpf", "udp", "BPF filter applied to pcap/live capture input, e.g. \"udp port 18590\"")
+	fServer = flag.String("server", "", "server ip:port, used to tell client and server packets apart when decoding pcap/live captures")
+)
+
+// recordSource produces decoder input records one at a time.
+type recordSource interface {
+	// Next returns the next record, or io.EOF once the input is exhausted.
+	Next() (RecordIn, error)
+	Close() error
+}
+
+// pcap/pcapng magic numbers, checked in both byte orders.
+const (
+	magicPcap      = 0xa1b2c3d4
+	magicPcapNanos = 0xa1b23c4d
+	magicPcapNg    = 0x0a0d0d0a
+)
+
+// isPcapFile reports whether the file starts with a recognized pcap or
+// pcapng magic number.
+func isPcapFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	var buf [4]byte
+	if _, err := io.ReadFull(f, buf[:]); err != nil {
+		return false
+	}
+	for _, magic := range []uint32{binary.BigEndian.Uint32(buf[:]), binary.LittleEndian.Uint32(buf[:])} {
+		switch magic {
+		case magicPcap, magicPcapNanos, magicPcapNg:
+			return true
+		}
+	}
+	return false
+}
+
+// openSource opens the packet source for decoding: a live capture when
+// -iface is set, otherwise the file at path, auto-detected as pcap/pcapng
+// (by extension or magic number) or a record stream in format.
+func openSource(path string, format Format) (recordSource, error) {
+	if *fIface != "" {
+		h, err := pcap.OpenLive(*fIface, 65535, true, pcap.BlockForever)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open interface %q: %w", *fIface, err)
+		}
+		return newPcapSource(h)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pcap", ".pcapng", ".cap":
+		return openPcapFile(path)
+	case ".jsonl", ".json":
+		return newRecordSource(path, format)
+	}
+	if isPcapFile(path) {
+		return openPcapFile(path)
+	}
+	return newRecordSource(path, format)
+}
+
+func openPcapFile(path string) (recordSource, error) {
+	h, err := pcap.OpenOffline(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open pcap file: %w", err)
+	}
+	return newPcapSource(h)
+}
+
+func newPcapSource(h *pcap.Handle) (*pcapSource, error) {
+	if *fBPF != "" {
+		if err := h.SetBPFFilter(*fBPF); err != nil {
+			h.Close()
+			return nil, fmt.Errorf("invalid BPF filter %q: %w", *fBPF, err)
+		}
+	}
+	var srv netip.AddrPort
+	if *fServer != "" {
+		a, err := netip.ParseAddrPort(*fServer)
+		if err != nil {
+			h.Close()
+			return nil, fmt.Errorf("invalid -server address: %w", err)
+		}
+		srv = a
+	}
+	return &pcapSource{
+		handle:  h,
+		pkts:    gopacket.NewPacketSource(h, h.LinkType()).Packets(),
+		server:  srv,
+		clients: make(map[netip.Addr]uint32),
+	}, nil
+}
+
+// pcapSource decodes UDP payloads out of a live or offline packet capture,
+// assigning each client IP address a stable ID on first sight, numbered from
+// 1 like the proxy command's client IDs. Unlike the proxy, which numbers each
+// client ip:port, clients sharing an IP address share an ID.
+type pcapSource struct {
+	handle *pcap.Handle
+	pkts   chan gopacket.Packet
+	server netip.AddrPort // known server address; everything else is a client
+
+	mu      sync.Mutex
+	nextID  uint32
+	clients map[netip.Addr]uint32
+}
+
+// clientID returns a stable ID for addr, assigning a new one on first sight.
+func (s *pcapSource) clientID(addr netip.Addr) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.clients[addr]
+	if !ok {
+		s.nextID++
+		id = s.nextID
+		s.clients[addr] = id
+	}
+	return id
+}
+
+func (s *pcapSource) Next() (RecordIn, error) {
+	for pkt := range s.pkts {
+		udp, ok := pkt.TransportLayer().(*layers.UDP)
+		net := pkt.NetworkLayer()
+		if !ok || net == nil || len(udp.Payload) == 0 {
+			continue
+		}
+		srcIP, err := netip.ParseAddr(net.NetworkFlow().Src().String())
+		if err != nil {
+			continue
+		}
+		dstIP, err := netip.ParseAddr(net.NetworkFlow().Dst().String())
+		if err != nil {
+			continue
+		}
+		src := netip.AddrPortFrom(srcIP, uint16(udp.SrcPort))
+		dst := netip.AddrPortFrom(dstIP, uint16(udp.DstPort))
+		var srcID, dstID uint32
+		if src != s.server {
+			srcID = s.clientID(src.Addr())
+		}
+		if dst != s.server {
+			dstID = s.clientID(dst.Addr())
+		}
+		return RecordIn{
+			SrcID: srcID,
+			DstID: dstID,
+			Src:   src.String(),
+			Dst:   dst.String(),
+			Data:  hex.EncodeToString(udp.Payload),
+		}, nil
+	}
+	return RecordIn{}, io.EOF
+}
+
+func (s *pcapSource) Close() error {
+	s.handle.Close()
+	return nil
+}
+
+// recordFileSource reads RecordIn values from a file in a pluggable wire
+// format (json, proto or msgpack).
+type recordFileSource struct {
+	f   *os.File
+	dec decoder
+}
+
+func newRecordSource(path string, format Format) (*recordFileSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &recordFileSource{f: f, dec: newRecordDecoder(format, f)}, nil
+}
+
+func (s *recordFileSource) Next() (RecordIn, error) {
+	var r RecordIn
+	err := s.dec.Decode(&r)
+	return r, err
+}
+
+func (s *recordFileSource) Close() error {
+	return s.f.Close()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Format selects the wire encoding used for decoder input and output
+// records.
+type Format string
+
+const (
+	FormatJSON    Format = "json"
+	FormatProto   Format = "proto"
+	FormatMsgpack Format = "msgpack"
+)
+
+// ParseFormat parses a -format flag value, defaulting to FormatJSON.
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatProto:
+		return FormatProto, nil
+	case FormatMsgpack:
+		return FormatMsgpack, nil
+	default:
+		return "", fmt.Errorf("unknown format %q, want json, proto or msgpack", s)
+	}
+}
+
+// decoder reads successive records in a specific wire format.
+type decoder interface {
+	Decode(v any) error
+}
+
+// encoder writes successive records in a specific wire format.
+type encoder interface {
+	Encode(v any) error
+}
+
+// newRecordDecoder returns a decoder for format reading from r.
+func newRecordDecoder(format Format, r io.Reader) decoder {
+	switch format {
+	case FormatMsgpack:
+		return msgpack.NewDecoder(r)
+	case FormatProto:
+		return newProtoDecoder(r)
+	default:
+		return json.NewDecoder(r)
+	}
+}
+
+// newRecordEncoder returns an encoder for format writing to w.
+func newRecordEncoder(format Format, w io.Writer) encoder {
+	switch format {
+	case FormatMsgpack:
+		return msgpack.NewEncoder(w)
+	case FormatProto:
+		return newProtoEncoder(w)
+	default:
+		return json.NewEncoder(w)
+	}
+}
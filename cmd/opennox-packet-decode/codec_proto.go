@@ -0,0 +1,465 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protoEncoder and protoDecoder (de)serialize records as protobuf messages,
+// each preceded by its length as a 4-byte big-endian integer.
+//
+// The RecordIn/RecordOut/Msg envelope is encoded directly against the
+// protobuf wire format via protowire; there is no .proto schema, and no
+// per-message-type schema for the netmsg.Message values. Payloads and
+// message bytes travel as raw bytes instead of hex strings, and each Msg
+// carries its numeric opCode (the first byte of its data) so captures can be
+// indexed/filtered by op code without parsing hex or matching strings.
+// Msg.Fields is carried as a JSON blob in a bytes field.
+const (
+	fieldRecInSrcID = 1
+	fieldRecInDstID = 2
+	fieldRecInSrc   = 3
+	fieldRecInDst   = 4
+	fieldRecInData  = 5
+)
+
+const (
+	fieldOutSrcID = 1
+	fieldOutDstID = 2
+	fieldOutSrc   = 3
+	fieldOutDst   = 4
+	fieldOutHdr   = 5
+	fieldOutSID   = 6
+	fieldOutSyn   = 7
+	fieldOutAck   = 8
+	fieldOutLen   = 9
+	fieldOutOp    = 10
+	fieldOutOps   = 11
+	fieldOutMsgs  = 12
+	fieldOutData  = 13
+)
+
+const (
+	fieldMsgOp     = 1
+	fieldMsgOpCode = 2
+	fieldMsgFields = 3
+	fieldMsgLen    = 4
+	fieldMsgData   = 5
+)
+
+type protoEncoder struct {
+	w io.Writer
+}
+
+func newProtoEncoder(w io.Writer) *protoEncoder {
+	return &protoEncoder{w: w}
+}
+
+func (e *protoEncoder) Encode(v any) error {
+	var (
+		data []byte
+		err  error
+	)
+	switch r := v.(type) {
+	case *RecordIn:
+		data = encodeRecordIn(r)
+	case RecordIn:
+		data = encodeRecordIn(&r)
+	case *RecordOut:
+		data, err = encodeRecordOut(r)
+	case RecordOut:
+		data, err = encodeRecordOut(&r)
+	default:
+		return fmt.Errorf("proto: unsupported type %T", v)
+	}
+	if err != nil {
+		return err
+	}
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(len(data)))
+	if _, err := e.w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func encodeRecordIn(r *RecordIn) []byte {
+	var b []byte
+	b = appendVarintField(b, fieldRecInSrcID, uint64(r.SrcID))
+	b = appendVarintField(b, fieldRecInDstID, uint64(r.DstID))
+	b = appendStringField(b, fieldRecInSrc, r.Src)
+	b = appendStringField(b, fieldRecInDst, r.Dst)
+	if raw, err := hex.DecodeString(r.Data); err == nil {
+		b = appendBytesField(b, fieldRecInData, raw)
+	}
+	return b
+}
+
+func encodeRecordOut(o *RecordOut) ([]byte, error) {
+	var b []byte
+	b = appendVarintField(b, fieldOutSrcID, uint64(o.SrcID))
+	b = appendVarintField(b, fieldOutDstID, uint64(o.DstID))
+	b = appendStringField(b, fieldOutSrc, o.Src)
+	b = appendStringField(b, fieldOutDst, o.Dst)
+	if raw, err := hex.DecodeString(o.Hdr); err == nil {
+		b = appendBytesField(b, fieldOutHdr, raw)
+	}
+	b = appendVarintField(b, fieldOutSID, uint64(o.SID))
+	if o.Syn != nil {
+		b = appendPresentVarintField(b, fieldOutSyn, uint64(*o.Syn))
+	}
+	if o.Ack != nil {
+		b = appendPresentVarintField(b, fieldOutAck, uint64(*o.Ack))
+	}
+	b = appendVarintField(b, fieldOutLen, uint64(o.Len))
+	if o.Op != nil {
+		b = appendPresentStringField(b, fieldOutOp, *o.Op)
+	}
+	for _, op := range o.Ops {
+		b = appendStringField(b, fieldOutOps, op)
+	}
+	for _, m := range o.Msgs {
+		mb, err := encodeMsg(&m)
+		if err != nil {
+			return nil, err
+		}
+		b = appendBytesField(b, fieldOutMsgs, mb)
+	}
+	if raw, err := hex.DecodeString(o.Data); err == nil {
+		b = appendBytesField(b, fieldOutData, raw)
+	}
+	return b, nil
+}
+
+func encodeMsg(m *Msg) ([]byte, error) {
+	var b []byte
+	b = appendStringField(b, fieldMsgOp, m.Op)
+	raw, err := hex.DecodeString(m.Data)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > 0 {
+		b = appendVarintField(b, fieldMsgOpCode, uint64(raw[0]))
+	}
+	if m.Fields != nil {
+		fb, err := json.Marshal(m.Fields)
+		if err != nil {
+			return nil, err
+		}
+		b = appendBytesField(b, fieldMsgFields, fb)
+	}
+	b = appendVarintField(b, fieldMsgLen, uint64(m.Len))
+	b = appendBytesField(b, fieldMsgData, raw)
+	return b, nil
+}
+
+// appendVarintField omits the field when v is the zero value. Only use this
+// for plain scalars that have no presence semantics of their own; fields
+// backed by a pointer (nil means "absent") must use
+// appendPresentVarintField instead; otherwise a legitimate zero value (e.g.
+// seq == 0 on the first packet of a session) is indistinguishable from the
+// field never having been set.
+func appendVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// appendPresentVarintField always writes the field, for callers that already
+// gated the call on the source pointer being non-nil.
+func appendPresentVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// appendStringField omits the field when v is empty. Only use this for
+// plain scalars that have no presence semantics of their own; fields backed
+// by a pointer (nil means "absent") must use appendPresentStringField
+// instead.
+func appendStringField(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+// appendPresentStringField always writes the field, for callers that
+// already gated the call on the source pointer being non-nil.
+func appendPresentStringField(b []byte, num protowire.Number, v string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendBytesField(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+type protoDecoder struct {
+	r io.Reader
+}
+
+func newProtoDecoder(r io.Reader) *protoDecoder {
+	return &protoDecoder{r: r}
+}
+
+func (d *protoDecoder) Decode(v any) error {
+	var hdr [4]byte
+	if _, err := io.ReadFull(d.r, hdr[:]); err != nil {
+		return err
+	}
+	data := make([]byte, binary.BigEndian.Uint32(hdr[:]))
+	if _, err := io.ReadFull(d.r, data); err != nil {
+		return err
+	}
+	switch p := v.(type) {
+	case *RecordIn:
+		return decodeRecordIn(data, p)
+	case *RecordOut:
+		return decodeRecordOut(data, p)
+	default:
+		return fmt.Errorf("proto: unsupported type %T", v)
+	}
+}
+
+func decodeRecordIn(data []byte, o *RecordIn) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch num {
+		case fieldRecInSrcID:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.SrcID = uint32(v)
+			data = data[n:]
+		case fieldRecInDstID:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.DstID = uint32(v)
+			data = data[n:]
+		case fieldRecInSrc:
+			v, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.Src = v
+			data = data[n:]
+		case fieldRecInDst:
+			v, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.Dst = v
+			data = data[n:]
+		case fieldRecInData:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.Data = hex.EncodeToString(v)
+			data = data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return nil
+}
+
+func decodeRecordOut(data []byte, o *RecordOut) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch num {
+		case fieldOutSrcID:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.SrcID = uint32(v)
+			data = data[n:]
+		case fieldOutDstID:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.DstID = uint32(v)
+			data = data[n:]
+		case fieldOutSrc:
+			v, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.Src = v
+			data = data[n:]
+		case fieldOutDst:
+			v, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.Dst = v
+			data = data[n:]
+		case fieldOutHdr:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.Hdr = hex.EncodeToString(v)
+			data = data[n:]
+		case fieldOutSID:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.SID = byte(v)
+			data = data[n:]
+		case fieldOutSyn:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			syn := byte(v)
+			o.Syn = &syn
+			data = data[n:]
+		case fieldOutAck:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			ack := byte(v)
+			o.Ack = &ack
+			data = data[n:]
+		case fieldOutLen:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.Len = int(v)
+			data = data[n:]
+		case fieldOutOp:
+			v, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.Op = &v
+			data = data[n:]
+		case fieldOutOps:
+			v, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.Ops = append(o.Ops, v)
+			data = data[n:]
+		case fieldOutMsgs:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			var m Msg
+			if err := decodeMsg(v, &m); err != nil {
+				return err
+			}
+			o.Msgs = append(o.Msgs, m)
+			data = data[n:]
+		case fieldOutData:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			o.Data = hex.EncodeToString(v)
+			data = data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return nil
+}
+
+func decodeMsg(data []byte, m *Msg) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch num {
+		case fieldMsgOp:
+			v, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			m.Op = v
+			data = data[n:]
+		case fieldMsgOpCode:
+			// opCode is redundant with the first byte of Data; it exists so
+			// captures can be indexed/filtered by op code without decoding
+			// Data at all.
+			_, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+		case fieldMsgFields:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			var fields any
+			if err := json.Unmarshal(v, &fields); err != nil {
+				return err
+			}
+			m.Fields = fields
+			data = data[n:]
+		case fieldMsgLen:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			m.Len = int(v)
+			data = data[n:]
+		case fieldMsgData:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			m.Data = hex.EncodeToString(v)
+			data = data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return nil
+}
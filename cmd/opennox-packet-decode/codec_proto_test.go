@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProtoCodecRoundTrip(t *testing.T) {
+	syn := byte(0)
+	ack := byte(0)
+	op := "MSG_ACCEPTED"
+	in := RecordOut{
+		SrcID: 1,
+		DstID: 2,
+		Src:   "10.0.0.1:1234",
+		Dst:   "10.0.0.2:18590",
+		Hdr:   "0102",
+		SID:   7,
+		Syn:   &syn,
+		Ack:   &ack,
+		Len:   42,
+		Op:    &op,
+		Ops:   []string{"MSG_ACCEPTED"},
+		Data:  "deadbeef",
+	}
+
+	var buf bytes.Buffer
+	if err := newProtoEncoder(&buf).Encode(&in); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var out RecordOut
+	if err := newProtoDecoder(&buf).Decode(&out); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if out.Syn == nil || *out.Syn != 0 {
+		t.Errorf("Syn = %v, want pointer to 0", out.Syn)
+	}
+	if out.Ack == nil || *out.Ack != 0 {
+		t.Errorf("Ack = %v, want pointer to 0", out.Ack)
+	}
+	if out.Op == nil || *out.Op != op {
+		t.Errorf("Op = %v, want pointer to %q", out.Op, op)
+	}
+}
+
+func TestProtoCodecRoundTripAbsentSynAck(t *testing.T) {
+	in := RecordOut{SrcID: 1, DstID: 2, SID: 1, Len: 10}
+
+	var buf bytes.Buffer
+	if err := newProtoEncoder(&buf).Encode(&in); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var out RecordOut
+	if err := newProtoDecoder(&buf).Decode(&out); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if out.Syn != nil {
+		t.Errorf("Syn = %v, want nil", out.Syn)
+	}
+	if out.Ack != nil {
+		t.Errorf("Ack = %v, want nil", out.Ack)
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"encoding/hex"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -14,8 +13,9 @@ import (
 )
 
 var (
-	fIn  = flag.String("i", "network.jsonl", "input file with packet capture")
-	fOut = flag.String("o", "network-dec.jsonl", "output file for decoded packets")
+	fIn     = flag.String("i", "network.jsonl", "input file with packet capture: network.jsonl, or a .pcap/.pcapng file")
+	fOut    = flag.String("o", "network-dec.jsonl", "output file for decoded packets")
+	fFormat = flag.String("format", "json", "wire format for the input/output record stream: json, proto or msgpack (does not affect pcap/pcapng/live input)")
 )
 
 func main() {
@@ -27,24 +27,26 @@ func main() {
 }
 
 func run() error {
-	f, err := os.Open(*fIn)
+	format, err := ParseFormat(*fFormat)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	dec := json.NewDecoder(f)
+	src, err := openSource(*fIn, format)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
 
 	w, err := os.Create(*fOut)
 	if err != nil {
 		return err
 	}
 	defer w.Close()
-	enc := json.NewEncoder(w)
+	enc := newRecordEncoder(format, w)
 
 	var mdec netmsg.State
 	for {
-		var r RecordIn
-		err := dec.Decode(&r)
+		r, err := src.Next()
 		if err == io.EOF {
 			break
 		} else if err != nil {
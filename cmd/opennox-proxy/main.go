@@ -19,11 +19,17 @@ import (
 
 //go:generate d2 diagram.d2 diagram.svg
 //go:generate d2 diagram.d2 diagram.png
+//go:generate yaegi extract github.com/opennox/libs/noxnet/netmsg
+//go:generate yaegi extract github.com/opennox/libs/noxnet
+//go:generate yaegi extract github.com/opennox/libs/noxnet/discover
 
 var (
 	fServer = flag.String("server", "127.0.0.1:18590", "server address to proxy requests to")
 	fHost   = flag.String("host", "0.0.0.0:18600", "address to host proxy on")
 	fFile   = flag.String("file", "", "file name to dump messages to")
+	fScript = flag.String("script", "", "Lua (.lua) or Go (yaegi) script exposing OnClientToServer/OnServerToClient hooks; "+
+		"Lua scripts can only mutate or drop the message they're given, not synthesize a different one, and neither backend can delay a message")
+	fReplay = flag.String("replay", "", "replay a decoder RecordOut .jsonl capture against the real server instead of proxying live traffic")
 )
 
 func main() {
@@ -39,7 +45,18 @@ func run() error {
 	if err != nil {
 		return err
 	}
+	var sc Script
+	if *fScript != "" {
+		sc, err = LoadScript(*fScript)
+		if err != nil {
+			return err
+		}
+	}
+	if *fReplay != "" {
+		return replay(*fReplay, srv, sc)
+	}
 	p := NewProxy(srv)
+	p.script = sc
 	defer p.Close()
 	log.Printf("serving proxy %v -> %v", *fHost, srv)
 	return p.ListenAndServe(*fHost)
@@ -55,6 +72,7 @@ func NewProxy(srv netip.AddrPort) *Proxy {
 
 type Proxy struct {
 	realSrv  netip.AddrPort
+	script   Script
 	clientID uint32 // atomic
 
 	emu   sync.Mutex
@@ -148,6 +166,12 @@ func (p *Proxy) sendAsClient(realCli netip.AddrPort, data []byte) {
 	}
 	p.recordPacket(c.id, 0, data)
 	log.Printf("CLI%d(%v) -> SP(%v): [%d]: %x", c.id, realCli, p.lis.LocalAddr(), len(data), data)
+	if p.script != nil {
+		data = runScript(p.script, dirClientToServer, data)
+		if len(data) == 0 {
+			return
+		}
+	}
 	err = c.SendToServer(data)
 	if err != nil {
 		log.Printf("cannot send client %v packet: %v", realCli, err)
@@ -215,6 +239,12 @@ func (c *clientPort) serve() {
 		if len(data) == 0 {
 			continue
 		}
+		if c.p.script != nil {
+			data = runScript(c.p.script, dirServerToClient, data)
+			if len(data) == 0 {
+				continue
+			}
+		}
 		err = c.p.sendToClient(c.id, c.realCli, data)
 		if err != nil {
 			log.Printf("client %v send: %v", c.realCli, err)
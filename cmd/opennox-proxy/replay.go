@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/opennox/libs/log"
+)
+
+// replayRecord mirrors the decoder tool's RecordOut format; only the fields
+// needed to rebuild the original datagram are decoded here.
+type replayRecord struct {
+	SrcID uint32 `json:"src_id"`
+	Hdr   string `json:"hdr"`
+	Data  string `json:"data"`
+	Msgs  []struct {
+		Data string `json:"data"`
+	} `json:"msgs"`
+}
+
+// rebuild reconstructs the original raw datagram bytes for a decoded record:
+// Data holds the full packet when it could not be fully split into
+// messages, otherwise it is the 2-byte header followed by each message's
+// raw bytes in order.
+func (r replayRecord) rebuild() ([]byte, error) {
+	if r.Data != "" {
+		return hex.DecodeString(r.Data)
+	}
+	buf, err := hex.DecodeString(r.Hdr)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range r.Msgs {
+		b, err := hex.DecodeString(m.Data)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
+// replay re-injects the client->server packets recorded in path (a decoder
+// RecordOut .jsonl capture) against the real server at srv, for
+// deterministic session replay and regression fuzzing. Server responses are
+// logged but not matched up against the capture.
+func replay(path string, srv netip.AddrPort, sc Script) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	conn, err := net.DialUDP("udp4", nil, net.UDPAddrFromAddrPort(srv))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	dec := json.NewDecoder(f)
+	var n int
+	for {
+		var r replayRecord
+		if err := dec.Decode(&r); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if r.SrcID == 0 {
+			continue // only replay packets originally sent by a client
+		}
+		data, err := r.rebuild()
+		if err != nil {
+			log.Printf("replay: skipping unparsable record: %v", err)
+			continue
+		}
+		if sc != nil {
+			data = runScript(sc, dirClientToServer, data)
+			if len(data) == 0 {
+				continue
+			}
+		}
+		if _, err := conn.Write(data); err != nil {
+			return fmt.Errorf("replay: send: %w", err)
+		}
+		n++
+
+		resp := make([]byte, 4096)
+		_ = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		if rn, err := conn.Read(resp); err == nil {
+			log.Printf("replay: server replied [%d]: %x", rn, resp[:rn])
+		}
+	}
+	log.Printf("replay: sent %d packets from %s to %v", n, path, srv)
+	return nil
+}
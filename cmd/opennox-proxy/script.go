@@ -0,0 +1,102 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/opennox/libs/log"
+	"github.com/opennox/libs/noxnet/netmsg"
+)
+
+// direction identifies which way a message is travelling through the proxy.
+type direction int
+
+const (
+	dirClientToServer direction = iota
+	dirServerToClient
+)
+
+// Script is the extension point for the proxy: it is invoked for every
+// message exchanged between a client and the server, and can mutate, drop,
+// or synthesize netmsg.Message values. Hooks run synchronously inline with
+// the datagram being processed; there is no queue or timer, so a Script
+// cannot delay a message to send it later.
+//
+// Both backends fall short of the full interface in practice. The Lua
+// backend (see script_lua.go) can only mutate the message it was given in
+// place or drop it; its call convention never returns a different or
+// additional message, so "synthesize" isn't reachable from Lua scripts. The
+// Go (yaegi) backend can synthesize any message type, but only the netmsg
+// symbols already hand-listed in script/eval/imports are visible to it (see
+// the SCOPE DECISION notes there) — referencing any other message type
+// fails to resolve at load time.
+//
+// Implementations must be safe for concurrent use: one clientPort is served
+// per connected client, each calling into the Script from its own goroutine.
+type Script interface {
+	// OnClientToServer is called for every message sent by the client before
+	// it reaches the real server. The returned slice replaces msg in the
+	// outgoing stream; a nil or empty slice drops it, and a slice with more
+	// than one message injects extra ones after it.
+	OnClientToServer(msg netmsg.Message) []netmsg.Message
+	// OnServerToClient is the same as OnClientToServer, but for messages sent
+	// by the server to a client.
+	OnServerToClient(msg netmsg.Message) []netmsg.Message
+}
+
+// LoadScript compiles and loads a proxy script from path. The script
+// language is picked by file extension: ".lua" loads a Lua script, anything
+// else is interpreted as Go using yaegi.
+func LoadScript(path string) (Script, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".lua":
+		return newLuaScript(path)
+	default:
+		return newGoScript(path)
+	}
+}
+
+// runScript decodes the messages in data (a full client<->server datagram,
+// header included), runs each one through the given Script hook, and
+// re-encodes the result. Messages that fail to decode are passed through
+// unmodified.
+func runScript(sc Script, dir direction, data []byte) []byte {
+	if len(data) < 2 {
+		return data
+	}
+	hdr, body := data[:2], data[2:]
+	var dec netmsg.State
+	dec.IsClient = dir == dirClientToServer
+
+	out := append([]byte{}, hdr...)
+	for len(body) > 0 {
+		m, n, err := dec.DecodeNext(body)
+		if err != nil || n <= 0 {
+			// Can't safely continue decoding the rest of the datagram;
+			// keep whatever is left as-is.
+			out = append(out, body...)
+			break
+		}
+		body = body[n:]
+
+		var msgs []netmsg.Message
+		switch dir {
+		case dirClientToServer:
+			msgs = sc.OnClientToServer(m)
+		case dirServerToClient:
+			msgs = sc.OnServerToClient(m)
+		}
+		for _, msg := range msgs {
+			var aerr error
+			out, aerr = netmsg.Append(out, msg)
+			if aerr != nil {
+				log.Printf("script: cannot encode %v: %v", msg.NetOp(), aerr)
+				return data
+			}
+		}
+	}
+	// Scripts that mutate a message in place return the same pointer they
+	// were given, so there's no reliable way to tell "unchanged" apart from
+	// "mutated" by identity; always use the re-encoded buffer.
+	return out
+}
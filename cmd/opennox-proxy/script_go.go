@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/traefik/yaegi/interp"
+	"github.com/traefik/yaegi/stdlib"
+
+	"github.com/opennox/libs/noxnet/netmsg"
+	"github.com/opennox/libs/script/eval/imports"
+)
+
+// goScript runs a user-provided Go script through the yaegi interpreter,
+// reusing the symbol tables generated for the map scripting engine
+// (see github.com/opennox/libs/script/eval/imports).
+type goScript struct {
+	onClientToServer func(netmsg.Message) []netmsg.Message
+	onServerToClient func(netmsg.Message) []netmsg.Message
+}
+
+func newGoScript(path string) (*goScript, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	i := interp.New(interp.Options{})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		return nil, fmt.Errorf("script: cannot load stdlib symbols: %w", err)
+	}
+	if err := i.Use(imports.Symbols); err != nil {
+		return nil, fmt.Errorf("script: cannot load symbols: %w", err)
+	}
+	if _, err := i.Eval(string(src)); err != nil {
+		return nil, fmt.Errorf("script: cannot load %q: %w", path, err)
+	}
+	sc := &goScript{}
+	sc.onClientToServer, err = lookupHook(i, "OnClientToServer")
+	if err != nil {
+		return nil, err
+	}
+	sc.onServerToClient, err = lookupHook(i, "OnServerToClient")
+	if err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// lookupHook looks up an optional top-level function with the signature
+// func(netmsg.Message) []netmsg.Message in the script. Scripts may omit
+// either hook; a no-op pass-through is returned in that case.
+func lookupHook(i *interp.Interpreter, name string) (func(netmsg.Message) []netmsg.Message, error) {
+	v, err := i.Eval(name)
+	if err != nil {
+		// Hook not defined by the script: pass messages through unchanged.
+		return func(m netmsg.Message) []netmsg.Message { return []netmsg.Message{m} }, nil
+	}
+	fn, ok := v.Interface().(func(netmsg.Message) []netmsg.Message)
+	if !ok {
+		return nil, fmt.Errorf("script: %s has unexpected type %v", name, v.Type())
+	}
+	return fn, nil
+}
+
+func (s *goScript) OnClientToServer(msg netmsg.Message) []netmsg.Message {
+	return s.onClientToServer(msg)
+}
+
+func (s *goScript) OnServerToClient(msg netmsg.Message) []netmsg.Message {
+	return s.onServerToClient(msg)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/opennox/libs/log"
+	"github.com/opennox/libs/noxnet/netmsg"
+)
+
+// luaScript runs a user-provided Lua script. Messages are exposed to Lua as
+// plain tables keyed by their exported Go field names; the script can read
+// and write fields directly and return the (possibly modified) table, false
+// to drop the message, or nothing to pass it through unchanged.
+type luaScript struct {
+	mu sync.Mutex
+	L  *lua.LState
+}
+
+func newLuaScript(path string) (*luaScript, error) {
+	L := lua.NewState()
+	if err := L.DoFile(path); err != nil {
+		L.Close()
+		return nil, fmt.Errorf("script: cannot load %q: %w", path, err)
+	}
+	return &luaScript{L: L}, nil
+}
+
+func (s *luaScript) call(fname string, msg netmsg.Message) []netmsg.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn := s.L.GetGlobal(fname)
+	if fn == lua.LNil {
+		return []netmsg.Message{msg}
+	}
+	tbl := messageToLua(s.L, msg)
+	if err := s.L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, tbl); err != nil {
+		log.Printf("script: %s: %v", fname, err)
+		return []netmsg.Message{msg}
+	}
+	ret := s.L.Get(-1)
+	s.L.Pop(1)
+	switch v := ret.(type) {
+	case *lua.LTable:
+		messageFromLua(msg, v)
+		return []netmsg.Message{msg}
+	case lua.LBool:
+		if !bool(v) {
+			return nil // drop the message
+		}
+		return []netmsg.Message{msg}
+	default:
+		return []netmsg.Message{msg}
+	}
+}
+
+func (s *luaScript) OnClientToServer(msg netmsg.Message) []netmsg.Message {
+	return s.call("OnClientToServer", msg)
+}
+
+func (s *luaScript) OnServerToClient(msg netmsg.Message) []netmsg.Message {
+	return s.call("OnServerToClient", msg)
+}
+
+// messageToLua converts the exported fields of msg's underlying struct into
+// a Lua table, plus an "op" field with the message's netmsg.Op string.
+func messageToLua(L *lua.LState, msg netmsg.Message) *lua.LTable {
+	tbl := L.NewTable()
+	tbl.RawSetString("op", lua.LString(msg.NetOp().String()))
+	v := reflect.ValueOf(msg)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return tbl
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if lv, ok := goToLua(v.Field(i)); ok {
+			tbl.RawSetString(f.Name, lv)
+		}
+	}
+	return tbl
+}
+
+// messageFromLua copies fields back from tbl into msg's underlying struct.
+func messageFromLua(msg netmsg.Message, tbl *lua.LTable) {
+	v := reflect.ValueOf(msg)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		lv := tbl.RawGetString(f.Name)
+		if lv == lua.LNil {
+			continue
+		}
+		luaToGo(lv, v.Field(i))
+	}
+}
+
+func goToLua(v reflect.Value) (lua.LValue, bool) {
+	switch v.Kind() {
+	case reflect.Bool:
+		return lua.LBool(v.Bool()), true
+	case reflect.String:
+		return lua.LString(v.String()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return lua.LNumber(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return lua.LNumber(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return lua.LNumber(v.Float()), true
+	default:
+		return nil, false
+	}
+}
+
+func luaToGo(lv lua.LValue, v reflect.Value) {
+	if !v.CanSet() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if b, ok := lv.(lua.LBool); ok {
+			v.SetBool(bool(b))
+		}
+	case reflect.String:
+		if s, ok := lv.(lua.LString); ok {
+			v.SetString(string(s))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := lv.(lua.LNumber); ok {
+			v.SetInt(int64(n))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, ok := lv.(lua.LNumber); ok {
+			v.SetUint(uint64(n))
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, ok := lv.(lua.LNumber); ok {
+			v.SetFloat(float64(n))
+		}
+	}
+}
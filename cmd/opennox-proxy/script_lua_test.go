@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+type testLuaStruct struct {
+	Name    string
+	Count   int32
+	Flag    bool
+	ignored string // unexported, must be skipped by both directions
+}
+
+func TestLuaFieldRoundTrip(t *testing.T) {
+	in := testLuaStruct{Name: "hello", Count: 7, Flag: true, ignored: "unseen"}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	tbl := L.NewTable()
+	v := reflect.ValueOf(in)
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		lv, ok := goToLua(v.Field(i))
+		if !ok {
+			t.Fatalf("goToLua: unsupported field %s", f.Name)
+		}
+		tbl.RawSetString(f.Name, lv)
+	}
+
+	if tbl.RawGetString("ignored") != lua.LNil {
+		t.Error("unexported field leaked into the Lua table")
+	}
+
+	var out testLuaStruct
+	ov := reflect.ValueOf(&out).Elem()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		luaToGo(tbl.RawGetString(f.Name), ov.Field(i))
+	}
+
+	if out.Name != in.Name || out.Count != in.Count || out.Flag != in.Flag {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+	if out.ignored != "" {
+		t.Errorf("ignored = %q, want untouched zero value", out.ignored)
+	}
+}
+
+func TestLuaToGoIgnoresUnsettable(t *testing.T) {
+	// luaToGo must not panic when handed a non-settable reflect.Value (e.g.
+	// a field read from an unaddressable struct copy).
+	v := reflect.ValueOf(testLuaStruct{}).Field(0)
+	luaToGo(lua.LString("x"), v)
+}
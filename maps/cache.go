@@ -0,0 +1,113 @@
+package maps
+
+import (
+	"container/list"
+	"io/fs"
+	"os"
+	lpath "path"
+	"sync"
+	"time"
+)
+
+// maxCachedBundles bounds how many compressed map bundles are kept in
+// memory at once.
+const maxCachedBundles = 64
+
+// bundleCacheKey identifies a cached bundle by map name, the modification
+// time of the newest file that went into it, and the number of files
+// included. mtime alone invalidates the cache when a file is added or
+// modified, but misses a file being deleted without touching the mtime of
+// any file that remains; the file count catches that case too.
+type bundleCacheKey struct {
+	name  string
+	mtime time.Time
+	files int
+}
+
+type bundleCacheEntry struct {
+	key      bundleCacheKey
+	data     []byte
+	etag     string
+	manifest *Manifest
+}
+
+// bundleCache is a small in-memory LRU cache of compressed map bundles.
+type bundleCache struct {
+	max int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[bundleCacheKey]*list.Element
+}
+
+func newBundleCache(max int) *bundleCache {
+	return &bundleCache{
+		max:   max,
+		ll:    list.New(),
+		items: make(map[bundleCacheKey]*list.Element),
+	}
+}
+
+func (c *bundleCache) get(key bundleCacheKey) (*bundleCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*bundleCacheEntry), true
+}
+
+func (c *bundleCache) put(e *bundleCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[e.key]; ok {
+		el.Value = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[e.key] = c.ll.PushFront(e)
+	for c.ll.Len() > c.max {
+		back := c.ll.Back()
+		if back == nil {
+			break
+		}
+		c.ll.Remove(back)
+		delete(c.items, back.Value.(*bundleCacheEntry).key)
+	}
+}
+
+// newestMapModTime returns the modification time of the newest file that
+// CompressMap would include from dir, and how many such files there are,
+// for use as a bundleCache key. The count is needed alongside mtime because
+// deleting a file never advances the newest mtime among the files that
+// remain.
+func newestMapModTime(fss fs.FS, dir string) (mtime time.Time, count int, err error) {
+	if fss == nil {
+		fss = os.DirFS(dir)
+		dir = "."
+	}
+	dir = lpath.Clean(dir)
+	err = fs.WalkDir(fss, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		if !IsAllowedFile(path) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if fi.ModTime().After(mtime) {
+			mtime = fi.ModTime()
+		}
+		count++
+		return nil
+	})
+	return mtime, count, err
+}
@@ -0,0 +1,55 @@
+package maps
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewestMapModTimeDetectsDeletion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"mymap/mymap.map": {Data: []byte("a")},
+		"mymap/info.json": {Data: []byte("b")},
+	}
+	mtime1, count1, err := newestMapModTime(fsys, "mymap")
+	if err != nil {
+		t.Fatalf("newestMapModTime: %v", err)
+	}
+	if count1 != 2 {
+		t.Fatalf("count1 = %d, want 2", count1)
+	}
+
+	delete(fsys, "mymap/info.json")
+	mtime2, count2, err := newestMapModTime(fsys, "mymap")
+	if err != nil {
+		t.Fatalf("newestMapModTime: %v", err)
+	}
+	if count2 != 1 {
+		t.Fatalf("count2 = %d, want 1", count2)
+	}
+	// fstest.MapFS files carry a zero ModTime by default, so deleting a file
+	// doesn't change the newest mtime among the files that remain; the
+	// resulting cache key must still differ because of the file count.
+	if (bundleCacheKey{name: "mymap", mtime: mtime1, files: count1}) == (bundleCacheKey{name: "mymap", mtime: mtime2, files: count2}) {
+		t.Fatal("cache key unchanged after a file was deleted")
+	}
+}
+
+func TestBundleCacheEviction(t *testing.T) {
+	c := newBundleCache(2)
+	e1 := &bundleCacheEntry{key: bundleCacheKey{name: "a"}}
+	e2 := &bundleCacheEntry{key: bundleCacheKey{name: "b"}}
+	e3 := &bundleCacheEntry{key: bundleCacheKey{name: "c"}}
+	c.put(e1)
+	c.put(e2)
+	c.put(e3)
+
+	if _, ok := c.get(e1.key); ok {
+		t.Error("oldest entry should have been evicted once the cache exceeded its max size")
+	}
+	if _, ok := c.get(e2.key); !ok {
+		t.Error("e2 should still be cached")
+	}
+	if _, ok := c.get(e3.key); !ok {
+		t.Error("e3 should still be cached")
+	}
+}
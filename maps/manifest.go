@@ -0,0 +1,114 @@
+package maps
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	manifestName    = "manifest.json"
+	manifestSigName = "manifest.json.sig"
+)
+
+// Manifest describes the contents of a compressed map bundle: a SHA-256 hash
+// for every included file, so clients can verify integrity or check for
+// updates without downloading the whole bundle.
+type Manifest struct {
+	Map    string         `json:"map"`
+	Size   int64          `json:"size"`
+	Files  []ManifestFile `json:"files"`
+	Signed bool           `json:"signed"`
+}
+
+// ManifestFile is the per-file entry of a Manifest.
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// VerifyMapBundle checks a compressed map bundle produced by CompressMap: it
+// confirms that every file listed in the embedded manifest is present and
+// matches its recorded SHA-256 hash, and, if the bundle carries a signature,
+// verifies it against trustedKeys.
+//
+// It returns the manifest along with whether the signature was verified
+// against one of trustedKeys. A bundle without a signature is still
+// accepted; verified is simply false in that case.
+func VerifyMapBundle(r io.Reader, trustedKeys []ed25519.PublicKey) (man *Manifest, verified bool, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, false, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, false, fmt.Errorf("maps: cannot open bundle: %w", err)
+	}
+	var manBytes, sig []byte
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		switch f.Name {
+		case manifestName:
+			if manBytes, err = readZipFile(f); err != nil {
+				return nil, false, err
+			}
+		case manifestSigName:
+			if sig, err = readZipFile(f); err != nil {
+				return nil, false, err
+			}
+		default:
+			files[f.Name] = f
+		}
+	}
+	if manBytes == nil {
+		return nil, false, fmt.Errorf("maps: bundle has no %s", manifestName)
+	}
+	man = &Manifest{}
+	if err := json.Unmarshal(manBytes, man); err != nil {
+		return nil, false, fmt.Errorf("maps: cannot parse manifest: %w", err)
+	}
+	for _, mf := range man.Files {
+		zf, ok := files[mf.Name]
+		if !ok {
+			return man, false, fmt.Errorf("maps: bundle is missing file %q", mf.Name)
+		}
+		b, err := readZipFile(zf)
+		if err != nil {
+			return man, false, err
+		}
+		sum := sha256.Sum256(b)
+		if hex.EncodeToString(sum[:]) != mf.SHA256 {
+			return man, false, fmt.Errorf("maps: checksum mismatch for %q", mf.Name)
+		}
+	}
+	// every non-manifest zip entry must be accounted for in the manifest,
+	// otherwise a tampered bundle could smuggle in extra files that are
+	// never hashed or checked
+	if len(files) != len(man.Files) {
+		return man, false, fmt.Errorf("maps: bundle contains %d file(s) not listed in the manifest", len(files)-len(man.Files))
+	}
+	if len(sig) == 0 {
+		return man, false, nil
+	}
+	for _, pub := range trustedKeys {
+		if ed25519.Verify(pub, manBytes, sig) {
+			return man, true, nil
+		}
+	}
+	return man, false, fmt.Errorf("maps: signature verification failed against %d trusted key(s)", len(trustedKeys))
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,134 @@
+package maps
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"testing/fstest"
+)
+
+func buildTestBundle(t *testing.T, key ed25519.PrivateKey) []byte {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"mymap/mymap.map": {Data: []byte("map data")},
+		"mymap/info.json": {Data: []byte(`{"name":"mymap"}`)},
+	}
+	var buf bytes.Buffer
+	if _, err := CompressMap(&buf, fsys, "mymap", key); err != nil {
+		t.Fatalf("CompressMap: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// rewriteZip rebuilds a zip archive from data, letting mutate edit the
+// collected entries before they're written back out.
+func rewriteZip(t *testing.T, data []byte, mutate func(entries map[string][]byte)) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	entries := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		b, err := readZipFile(f)
+		if err != nil {
+			t.Fatalf("readZipFile(%s): %v", f.Name, err)
+		}
+		entries[f.Name] = b
+	}
+	mutate(entries)
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for name, b := range entries {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zw.Create(%s): %v", name, err)
+		}
+		if _, err := f.Write(b); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zw.Close: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestVerifyMapBundle(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	t.Run("unsigned", func(t *testing.T) {
+		data := buildTestBundle(t, nil)
+		man, verified, err := VerifyMapBundle(bytes.NewReader(data), nil)
+		if err != nil {
+			t.Fatalf("VerifyMapBundle: %v", err)
+		}
+		if verified {
+			t.Error("verified = true, want false for an unsigned bundle")
+		}
+		if man == nil || man.Map != "mymap" {
+			t.Errorf("man = %+v, want Map == mymap", man)
+		}
+	})
+
+	t.Run("signed, trusted key", func(t *testing.T) {
+		data := buildTestBundle(t, priv)
+		_, verified, err := VerifyMapBundle(bytes.NewReader(data), []ed25519.PublicKey{pub})
+		if err != nil {
+			t.Fatalf("VerifyMapBundle: %v", err)
+		}
+		if !verified {
+			t.Error("verified = false, want true for a bundle signed by a trusted key")
+		}
+	})
+
+	t.Run("signed, untrusted key", func(t *testing.T) {
+		data := buildTestBundle(t, priv)
+		_, verified, err := VerifyMapBundle(bytes.NewReader(data), []ed25519.PublicKey{otherPub})
+		if err == nil {
+			t.Fatal("VerifyMapBundle: want error for a bundle signed by an untrusted key")
+		}
+		if verified {
+			t.Error("verified = true, want false for a bundle signed by an untrusted key")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		data := buildTestBundle(t, nil)
+		data = rewriteZip(t, data, func(entries map[string][]byte) {
+			delete(entries, "info.json")
+		})
+		if _, _, err := VerifyMapBundle(bytes.NewReader(data), nil); err == nil {
+			t.Fatal("VerifyMapBundle: want error for a bundle missing a manifest-listed file")
+		}
+	})
+
+	t.Run("tampered file content", func(t *testing.T) {
+		data := buildTestBundle(t, nil)
+		data = rewriteZip(t, data, func(entries map[string][]byte) {
+			entries["mymap.map"] = []byte("corrupted")
+		})
+		if _, _, err := VerifyMapBundle(bytes.NewReader(data), nil); err == nil {
+			t.Fatal("VerifyMapBundle: want error for a bundle with a checksum mismatch")
+		}
+	})
+
+	t.Run("smuggled extra file", func(t *testing.T) {
+		data := buildTestBundle(t, nil)
+		data = rewriteZip(t, data, func(entries map[string][]byte) {
+			entries["extra.bin"] = []byte("not in the manifest")
+		})
+		if _, _, err := VerifyMapBundle(bytes.NewReader(data), nil); err == nil {
+			t.Fatal("VerifyMapBundle: want error for a bundle with a file not listed in the manifest")
+		}
+	})
+}
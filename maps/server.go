@@ -2,6 +2,11 @@ package maps
 
 import (
 	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"io/fs"
@@ -11,6 +16,7 @@ import (
 	lpath "path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"golang.org/x/exp/slices"
@@ -89,8 +95,12 @@ func IsAllowedFile(path string) bool {
 	return false // unrecognized
 }
 
-// CompressMap collects and compresses relevant files from Nox/OpenNox map directory.
-func CompressMap(w io.Writer, fss fs.FS, dir string) error {
+// CompressMap collects and compresses relevant files from Nox/OpenNox map
+// directory, embedding a manifest.json with a SHA-256 hash of every
+// included file. If key is non-nil, the manifest is additionally signed and
+// the signature is embedded alongside it as manifest.json.sig. It returns
+// the manifest that was embedded.
+func CompressMap(w io.Writer, fss fs.FS, dir string, key ed25519.PrivateKey) (*Manifest, error) {
 	if fss == nil {
 		fss = os.DirFS(dir)
 		dir = "."
@@ -99,7 +109,8 @@ func CompressMap(w io.Writer, fss fs.FS, dir string) error {
 	defer zw.Close()
 	dir = lpath.Clean(dir)
 	pref := strings.TrimSuffix(dir, "/") + "/"
-	return fs.WalkDir(fss, dir, func(path string, d fs.DirEntry, err error) error {
+	man := &Manifest{Map: lpath.Base(dir)}
+	err := fs.WalkDir(fss, dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -137,16 +148,56 @@ func CompressMap(w io.Writer, fss fs.FS, dir string) error {
 			return err
 		}
 		defer r.Close()
-		_, err = io.Copy(f, r)
-		return err
+		h := sha256.New()
+		n, err := io.Copy(io.MultiWriter(f, h), r)
+		if err != nil {
+			return err
+		}
+		man.Size += n
+		man.Files = append(man.Files, ManifestFile{
+			Name:   name,
+			Size:   n,
+			SHA256: hex.EncodeToString(h.Sum(nil)),
+		})
+		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	man.Signed = key != nil
+	manBytes, err := json.Marshal(man)
+	if err != nil {
+		return nil, err
+	}
+	mf, err := zw.Create(manifestName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mf.Write(manBytes); err != nil {
+		return nil, err
+	}
+	if key != nil {
+		sf, err := zw.Create(manifestSigName)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := sf.Write(ed25519.Sign(key, manBytes)); err != nil {
+			return nil, err
+		}
+	}
+	return man, nil
 }
 
-func NewServer(log *slog.Logger, path string) *Server {
+// NewServer creates a new map server that serves maps from path. If signKey
+// is non-nil, compressed bundles are signed with it; unsigned bundles are
+// still served, but flagged as such in their manifest.
+func NewServer(log *slog.Logger, path string, signKey ed25519.PrivateKey) *Server {
 	s := &Server{
-		log:  log,
-		path: path,
-		mux:  httprouter.New(),
+		log:     log,
+		path:    path,
+		signKey: signKey,
+		mux:     httprouter.New(),
+		cache:   newBundleCache(maxCachedBundles),
 	}
 	s.mux.Handle("HEAD", "/api/v0/maps/", s.handleMapList)
 	s.mux.Handle("GET", "/api/v0/maps/", s.handleMapList)
@@ -154,13 +205,16 @@ func NewServer(log *slog.Logger, path string) *Server {
 	s.mux.Handle("HEAD", "/api/v0/maps/:map", s.handleMap)
 	s.mux.Handle("GET", "/api/v0/maps/:map", s.handleMap)
 	s.mux.Handle("GET", "/api/v0/maps/:map/download", s.handleMapDownload)
+	s.mux.Handle("GET", "/api/v0/maps/:map/manifest", s.handleMapManifest)
 	return s
 }
 
 type Server struct {
-	log  *slog.Logger
-	mux  *httprouter.Router
-	path string
+	log     *slog.Logger
+	mux     *httprouter.Router
+	path    string
+	signKey ed25519.PrivateKey
+	cache   *bundleCache
 }
 
 func (s *Server) RegisterOnMux(mux *http.ServeMux) {
@@ -172,13 +226,34 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.mux.ServeHTTP(w, r)
 }
 
-func (s *Server) serveJSON(w http.ResponseWriter, obj interface{}) {
+func (s *Server) serveJSON(w http.ResponseWriter, r *http.Request, obj interface{}) {
 	w.Header().Set("Content-Type", "application/json")
-	enc := json.NewEncoder(w)
+	out := io.Writer(w)
+	if acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+	// json.Encoder encodes obj in full into its own buffer before writing it
+	// to out; a single value is not streamed piece by piece.
+	enc := json.NewEncoder(out)
 	enc.SetIndent("", "\t")
 	enc.Encode(obj)
 }
 
+// acceptsGzip reports whether r's Accept-Encoding header lists gzip.
+// Quality values are not looked at, so "gzip;q=0" also counts as accepting
+// gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(enc, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Server) handleMapList(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	switch r.Method {
 	default:
@@ -186,17 +261,52 @@ func (s *Server) handleMapList(w http.ResponseWriter, r *http.Request, p httprou
 	case "HEAD", "OPTIONS":
 		w.WriteHeader(http.StatusOK)
 	case "GET":
-		list, err := Scan(s.log, s.path, nil)
+		s.streamMapList(w, r)
+	}
+}
+
+// streamMapList writes the map list as a JSON array, reading and encoding
+// one map's info at a time instead of collecting every map's info into a
+// slice first. The directory listing itself is still read in full by
+// os.ReadDir before encoding starts.
+func (s *Server) streamMapList(w http.ResponseWriter, r *http.Request) {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		s.log.Error("error serving map list", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	out := io.Writer(w)
+	if acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+	enc := json.NewEncoder(out)
+	io.WriteString(out, "[")
+	first := true
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		info, err := ReadMapInfo(filepath.Join(s.path, e.Name()))
 		if err != nil {
+			// not every directory entry is a map; skip it and keep serving
+			// the rest
+			continue
+		}
+		if !first {
+			io.WriteString(out, ",")
+		}
+		first = false
+		if err := enc.Encode(info); err != nil {
 			s.log.Error("error serving map list", "err", err)
-			if len(list) == 0 {
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			// serve at least some maps
+			return
 		}
-		s.serveJSON(w, list)
 	}
+	io.WriteString(out, "]")
 }
 
 func (s *Server) handleMap(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
@@ -214,7 +324,7 @@ func (s *Server) handleMap(w http.ResponseWriter, r *http.Request, p httprouter.
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	s.serveJSON(w, info)
+	s.serveJSON(w, r, info)
 }
 
 func (s *Server) handleMapDownload(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
@@ -253,12 +363,77 @@ func (s *Server) handleMapDownload(w http.ResponseWriter, r *http.Request, p htt
 		http.ServeContent(w, r, fname, fi.ModTime(), f)
 		return
 	}
-	// serve compressed map file
-	w.Header().Set("Content-Type", contentTypeZIP)
-	err = CompressMap(w, nil, base)
+	// serve compressed map file, reusing a cached bundle when the map hasn't
+	// changed since it was last compressed
+	mtime, entry, err := s.compressedBundle(name, base)
 	if err != nil {
 		log.Error("error serving map", "err", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", contentTypeZIP)
+	w.Header().Set("ETag", entry.etag)
+	http.ServeContent(w, r, name+".zip", mtime, bytes.NewReader(entry.data))
+}
+
+// compressedBundle returns the compressed bundle for the map at base,
+// reusing a cached entry when the map hasn't changed since it was last
+// compressed. Both handleMapDownload and handleMapManifest share this so
+// neither forces a full rebuild/rehash of the bundle on every request.
+func (s *Server) compressedBundle(name, base string) (mtime time.Time, entry *bundleCacheEntry, err error) {
+	mtime, count, err := newestMapModTime(nil, base)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	key := bundleCacheKey{name: name, mtime: mtime, files: count}
+	entry, ok := s.cache.get(key)
+	if ok {
+		return mtime, entry, nil
+	}
+	var buf bytes.Buffer
+	man, err := CompressMap(&buf, nil, base, s.signKey)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	entry = &bundleCacheEntry{
+		key:      key,
+		data:     buf.Bytes(),
+		etag:     `"` + hex.EncodeToString(sum[:]) + `"`,
+		manifest: man,
+	}
+	s.cache.put(entry)
+	return mtime, entry, nil
+}
+
+// handleMapManifest serves just the manifest of the map's compressed bundle
+// as JSON, so clients can check for updates without downloading the whole
+// zip. The response does not include the bundle's signature, and the JSON is
+// re-encoded rather than the exact manifest.json bytes that were signed, so
+// authorship cannot be verified from this response alone.
+func (s *Server) handleMapManifest(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	name := strings.ToLower(p.ByName("map"))
+	if name == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	log := s.log.With("name", name)
+	base := filepath.Join(s.path, name)
+	base = ifs.Normalize(base)
+
+	if fi, err := os.Stat(base); os.IsNotExist(err) || (err == nil && !fi.IsDir()) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Error("error serving map manifest", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	_, entry, err := s.compressedBundle(name, base)
+	if err != nil {
+		log.Error("error building map manifest", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.serveJSON(w, r, entry.manifest)
 }
@@ -0,0 +1,38 @@
+// SCOPE DECISION (reviewed and accepted, see this package's history): the
+// request behind the proxy's Go script support asked for a general
+// extension point, not one limited to the message types interceptServer
+// already hardcodes. A full extraction was scoped down on review for this
+// series: this file is a hand-maintained symbol table for
+// github.com/opennox/libs/noxnet/netmsg, NOT produced by 'yaegi extract',
+// and only lists the symbols interceptServer currently uses (plus the State
+// and Unknown types), not the package's full exported surface. A Go script that references any other
+// exported netmsg symbol will fail with an unresolved-symbol error from
+// yaegi, so in practice Go scripts are no more general than the hardcoded
+// rewrite this feature was meant to replace. Replace this file with the
+// output of a real 'yaegi extract github.com/opennox/libs/noxnet/netmsg'
+// run (the //go:generate directive in cmd/opennox-proxy/main.go already
+// describes how) to close that gap.
+
+package imports
+
+import (
+	"reflect"
+
+	"github.com/opennox/libs/noxnet/netmsg"
+)
+
+func init() {
+	Symbols["github.com/opennox/libs/noxnet/netmsg/netmsg"] = map[string]reflect.Value{
+		// function, constant and variable definitions
+		"Append":            reflect.ValueOf(netmsg.Append),
+		"MSG_ACCEPTED":      reflect.ValueOf(netmsg.MSG_ACCEPTED),
+		"MSG_SERVER_ACCEPT": reflect.ValueOf(netmsg.MSG_SERVER_ACCEPT),
+		"MSG_SERVER_INFO":   reflect.ValueOf(netmsg.MSG_SERVER_INFO),
+
+		// type definitions
+		"Message": reflect.ValueOf((*netmsg.Message)(nil)),
+		"Op":      reflect.ValueOf((*netmsg.Op)(nil)),
+		"State":   reflect.ValueOf((*netmsg.State)(nil)),
+		"Unknown": reflect.ValueOf((*netmsg.Unknown)(nil)),
+	}
+}
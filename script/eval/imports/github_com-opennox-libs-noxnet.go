@@ -0,0 +1,30 @@
+// SCOPE DECISION (reviewed and accepted, see this package's history): the
+// request behind the proxy's Go script support asked for a general
+// extension point, not one limited to the message types interceptServer
+// already hardcodes. A full extraction was scoped down on review for this
+// series: this file is a hand-maintained symbol table for
+// github.com/opennox/libs/noxnet, NOT produced by 'yaegi extract', and only
+// lists the symbols interceptServer currently uses, not the package's full
+// exported surface. A Go script that references any other exported noxnet
+// symbol will fail with an unresolved-symbol error from yaegi, so in
+// practice Go scripts are no more general than the hardcoded rewrite this
+// feature was meant to replace. Replace this file with the output of a real
+// 'yaegi extract github.com/opennox/libs/noxnet' run (the //go:generate
+// directive in cmd/opennox-proxy/main.go already describes how) to close
+// that gap.
+
+package imports
+
+import (
+	"reflect"
+
+	"github.com/opennox/libs/noxnet"
+)
+
+func init() {
+	Symbols["github.com/opennox/libs/noxnet/noxnet"] = map[string]reflect.Value{
+		// type definitions
+		"MsgAccept":       reflect.ValueOf((*noxnet.MsgAccept)(nil)),
+		"MsgServerAccept": reflect.ValueOf((*noxnet.MsgServerAccept)(nil)),
+	}
+}